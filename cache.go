@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const defaultCacheLocation = "cache"
+const defaultCacheTTL = 10 * time.Minute
+
+// errTooOld is returned by loadFromDisk when a cache entry exists but is
+// older than the configured TTL.
+var errTooOld = errors.New("cache entry too old")
+
+func cacheLocation() string {
+	if config.CacheLocation == "" {
+		return defaultCacheLocation
+	}
+	return config.CacheLocation
+}
+
+func cacheTTL() time.Duration {
+	if config.CacheTTL == "" {
+		return defaultCacheTTL
+	}
+	ttl, err := time.ParseDuration(config.CacheTTL)
+	if err != nil {
+		return defaultCacheTTL
+	}
+	return ttl
+}
+
+// cacheKey rounds the coordinates so nearby favourite cities share a cache entry.
+func cacheKey(coordinates Coordinates) string {
+	return fmt.Sprintf("%.2f_%.2f.json", coordinates.Lat, coordinates.Lon)
+}
+
+func cachePath(coordinates Coordinates) string {
+	return filepath.Join(cacheLocation(), cacheKey(coordinates))
+}
+
+func loadFromDisk(coordinates Coordinates) (WeatherResponse, error) {
+	path := cachePath(coordinates)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return WeatherResponse{}, err
+	}
+
+	if time.Since(info.ModTime()) > cacheTTL() {
+		return WeatherResponse{}, errTooOld
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return WeatherResponse{}, err
+	}
+
+	var result WeatherResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return WeatherResponse{}, err
+	}
+
+	return result, nil
+}
+
+func saveToDisk(coordinates Coordinates, data []byte) error {
+	if err := os.MkdirAll(cacheLocation(), 0755); err != nil {
+		return fmt.Errorf("Failed to create cache directory: %s", err)
+	}
+
+	err := os.WriteFile(cachePath(coordinates), data, 0666)
+	if err != nil {
+		return fmt.Errorf("Failed to write cache file: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeRoundTripper struct {
+	calls    int
+	body     string
+	fallback http.RoundTripper
+}
+
+// RoundTrip only fakes requests to the OpenWeatherMap API, so it can be
+// installed as http.DefaultTransport without breaking httptest servers used
+// in the same test.
+func (f *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.Contains(req.URL.Host, "openweathermap.org") {
+		return f.fallback.RoundTrip(req)
+	}
+
+	f.calls++
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(f.body)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+const fakeWeatherJSON = `{"weather":[{"description":"clear sky"}],"main":{"temp":10,"feels_like":9},"name":"London","sys":{"country":"GB"}}`
+
+func withFakeTransport(t *testing.T, body string) *fakeRoundTripper {
+	t.Helper()
+	original := http.DefaultTransport
+	rt := &fakeRoundTripper{body: body, fallback: original}
+	http.DefaultTransport = rt
+	t.Cleanup(func() { http.DefaultTransport = original })
+	return rt
+}
+
+func TestSaveAndLoadFromDisk(t *testing.T) {
+	config.CacheLocation = t.TempDir()
+	coords := Coordinates{Lat: 51.5, Lon: -0.1}
+
+	if err := saveToDisk(coords, []byte(fakeWeatherJSON)); err != nil {
+		t.Fatalf("saveToDisk failed: %s", err)
+	}
+
+	result, err := loadFromDisk(coords)
+	if err != nil {
+		t.Fatalf("loadFromDisk failed: %s", err)
+	}
+	if result.City != "London" {
+		t.Fatalf("expected City to be London, got %s", result.City)
+	}
+}
+
+func TestLoadFromDiskTooOld(t *testing.T) {
+	config.CacheLocation = t.TempDir()
+	config.CacheTTL = "1ms"
+	coords := Coordinates{Lat: 51.5, Lon: -0.1}
+
+	if err := saveToDisk(coords, []byte(fakeWeatherJSON)); err != nil {
+		t.Fatalf("saveToDisk failed: %s", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := loadFromDisk(coords); err != errTooOld {
+		t.Fatalf("expected errTooOld, got %v", err)
+	}
+}
+
+func TestFetchWeatherUsesCacheOnSecondCall(t *testing.T) {
+	config.CacheLocation = t.TempDir()
+	config.CacheTTL = defaultCacheTTL.String()
+	rt := withFakeTransport(t, fakeWeatherJSON)
+
+	coords := Coordinates{Lat: 60.17, Lon: 24.94}
+	weatherChan := make(chan WeatherResult, 1)
+	errorChan := make(chan error, 1)
+
+	fetchWeather(coords, weatherChan, errorChan)
+	select {
+	case err := <-errorChan:
+		t.Fatalf("unexpected error: %s", err)
+	case <-weatherChan:
+	}
+
+	fetchWeather(coords, weatherChan, errorChan)
+	select {
+	case err := <-errorChan:
+		t.Fatalf("unexpected error: %s", err)
+	case <-weatherChan:
+	}
+
+	if rt.calls != 1 {
+		t.Fatalf("expected 1 HTTP call, got %d", rt.calls)
+	}
+}
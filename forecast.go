@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+type DailyForecast struct {
+	Date         string
+	TempMin      float32
+	TempMax      float32
+	Description  string
+	PrecipChance float32
+	MoonPhase    float32
+}
+
+type Alert struct {
+	SenderName  string
+	Event       string
+	Description string
+}
+
+type MoonPhase struct {
+	Phase float32
+}
+
+type ForecastResult struct {
+	Daily  []DailyForecast
+	Alerts []Alert
+	Moon   MoonPhase
+}
+
+type oneCallTemp struct {
+	Min float32 `json:"min"`
+	Max float32 `json:"max"`
+}
+
+type oneCallDaily struct {
+	Dt        int64            `json:"dt"`
+	Temp      oneCallTemp      `json:"temp"`
+	Weather   []WeatherDetails `json:"weather"`
+	Pop       float32          `json:"pop"`
+	MoonPhase float32          `json:"moon_phase"`
+}
+
+type oneCallAlert struct {
+	SenderName  string `json:"sender_name"`
+	Event       string `json:"event"`
+	Description string `json:"description"`
+}
+
+type oneCallResponse struct {
+	Daily  []oneCallDaily `json:"daily"`
+	Alerts []oneCallAlert `json:"alerts"`
+}
+
+// fetchForecast calls the OneCall 3.0 endpoint and returns the daily
+// forecast, moon phase, and any active alerts for the given coordinates.
+func fetchForecast(coordinates Coordinates) (ForecastResult, error) {
+	url := fmt.Sprintf("https://api.openweathermap.org/data/3.0/onecall?lat=%f&lon=%f&units=metric&lang=%s&exclude=current,minutely,hourly&appid=%s", coordinates.Lat, coordinates.Lon, config.Language, API_KEY)
+
+	data, err := fetchData(url)
+	if err != nil {
+		return ForecastResult{}, err
+	}
+
+	var result oneCallResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return ForecastResult{}, fmt.Errorf("Failed to parse forecast response: %s", err)
+	}
+
+	if len(result.Daily) == 0 {
+		return ForecastResult{}, errors.New("Not found")
+	}
+
+	forecast := ForecastResult{Moon: MoonPhase{Phase: result.Daily[0].MoonPhase}}
+	for _, d := range result.Daily {
+		description := ""
+		if len(d.Weather) > 0 {
+			description = d.Weather[0].Description
+		}
+		forecast.Daily = append(forecast.Daily, DailyForecast{
+			Date:         time.Unix(d.Dt, 0).UTC().Format("2006-01-02"),
+			TempMin:      d.Temp.Min,
+			TempMax:      d.Temp.Max,
+			Description:  description,
+			PrecipChance: d.Pop * 100,
+			MoonPhase:    d.MoonPhase,
+		})
+	}
+
+	for _, a := range result.Alerts {
+		forecast.Alerts = append(forecast.Alerts, Alert{
+			SenderName:  a.SenderName,
+			Event:       a.Event,
+			Description: a.Description,
+		})
+	}
+
+	return forecast, nil
+}
+
+// moonPhaseForDate looks up the moon phase for date (format "2006-01-02"),
+// or today's phase when date is empty.
+func moonPhaseForDate(forecast ForecastResult, date string) (float32, error) {
+	if date == "" {
+		return forecast.Moon.Phase, nil
+	}
+	for _, d := range forecast.Daily {
+		if d.Date == date {
+			return d.MoonPhase, nil
+		}
+	}
+	return 0, fmt.Errorf("No moon phase data available for %s", date)
+}
+
+var moonPhaseGlyphs = []string{"🌑", "🌒", "🌓", "🌔", "🌕", "🌖", "🌗", "🌘"}
+
+func moonPhaseGlyph(phase float32) string {
+	index := int(phase * float32(len(moonPhaseGlyphs)))
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(moonPhaseGlyphs) {
+		index = len(moonPhaseGlyphs) - 1
+	}
+	return moonPhaseGlyphs[index]
+}
+
+func printForecast(forecast ForecastResult, days int) {
+	if days > len(forecast.Daily) {
+		days = len(forecast.Daily)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+	fmt.Fprintln(w, "\nDate\tMin\tMax\tDescription\tPrecip")
+	for i := 0; i < days; i++ {
+		d := forecast.Daily[i]
+		fmt.Fprintf(w, "%s\t%.1f℃\t%.1f℃\t%s\t%.0f%%\n", d.Date, d.TempMin, d.TempMax, d.Description, d.PrecipChance)
+	}
+	w.Flush()
+}
+
+func printMoonPhase(phase float32) {
+	fmt.Printf("\nMoon phase: %s (%.2f)\n\n", moonPhaseGlyph(phase), phase)
+}
+
+func printAlerts(alerts []Alert) {
+	if len(alerts) == 0 {
+		fmt.Println("No active weather alerts")
+		return
+	}
+	for _, a := range alerts {
+		fmt.Printf("\n%s: %s\n%s\n", a.SenderName, a.Event, a.Description)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formatter renders a batch of weather results to stdout.
+type Formatter interface {
+	Format(results []WeatherResult)
+}
+
+// PrettyFormatter reproduces the original printResult output.
+type PrettyFormatter struct{}
+
+func (PrettyFormatter) Format(results []WeatherResult) {
+	for _, result := range results {
+		printResult(result)
+	}
+}
+
+// JSONFormatter emits the results as a JSON array, useful for scripting.
+type JSONFormatter struct{}
+
+func (JSONFormatter) Format(results []WeatherResult) {
+	data, err := json.Marshal(results)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(string(data))
+}
+
+// OneLineFormatter renders a compact, wttr.in-style summary per city.
+type OneLineFormatter struct{}
+
+func (OneLineFormatter) Format(results []WeatherResult) {
+	for _, result := range results {
+		fmt.Println(oneLineString(result))
+	}
+}
+
+func oneLineString(result WeatherResult) string {
+	return fmt.Sprintf("%s: %.0f°C, feels %.0f°C, %s", result.City, result.Temperature, result.FeelsLike, result.Description)
+}
+
+// TableFormatter aligns multi-city output into a single tabwriter block.
+type TableFormatter struct{}
+
+func (TableFormatter) Format(results []WeatherResult) {
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+	fmt.Fprintln(w, "City\tCountry\tTemp\tFeels Like\tDescription")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%.1f°C\t%.1f°C\t%s\n", result.City, result.Country, result.Temperature, result.FeelsLike, result.Description)
+	}
+	w.Flush()
+}
+
+func isFormatName(name string) bool {
+	switch strings.ToLower(name) {
+	case "pretty", "json", "oneline", "table":
+		return true
+	default:
+		return false
+	}
+}
+
+func getFormatter(name string) (Formatter, error) {
+	switch strings.ToLower(name) {
+	case "", "pretty":
+		return PrettyFormatter{}, nil
+	case "json":
+		return JSONFormatter{}, nil
+	case "oneline":
+		return OneLineFormatter{}, nil
+	case "table":
+		return TableFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("Unknown format: %s", name)
+	}
+}
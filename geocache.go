@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultGeoCacheFile = "geocache.json"
+const geoCacheRateLimit = 200 * time.Millisecond
+
+// GeoCache memoizes fetchLocationData results so repeated lookups for the
+// same city never re-hit the geocoding endpoint, and throttles the lookups
+// that do go out so bursts of new cities respect the API's rate limit.
+type GeoCache struct {
+	filename string
+	data     map[string]Coordinates
+	mu       sync.Mutex
+	ticker   *time.Ticker
+}
+
+func newGeoCache(filename string) *GeoCache {
+	return &GeoCache{
+		filename: filename,
+		data:     make(map[string]Coordinates),
+		ticker:   time.NewTicker(geoCacheRateLimit),
+	}
+}
+
+var geoCache = newGeoCache(defaultGeoCacheFile)
+
+func geoCacheKey(city, country string) string {
+	return fmt.Sprintf("%s,%s", strings.ToLower(city), strings.ToLower(country))
+}
+
+func (g *GeoCache) load() error {
+	data, err := os.ReadFile(g.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return json.Unmarshal(data, &g.data)
+}
+
+func (g *GeoCache) save() error {
+	g.mu.Lock()
+	data, err := json.Marshal(g.data)
+	g.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(g.filename, data, 0666)
+}
+
+// decode returns the coordinates for city/country, serving from the cache
+// when possible and otherwise fetching them, gated by the rate-limit ticker.
+func (g *GeoCache) decode(city, country string) (Coordinates, error) {
+	key := geoCacheKey(city, country)
+
+	g.mu.Lock()
+	coordinates, ok := g.data[key]
+	g.mu.Unlock()
+	if ok {
+		return coordinates, nil
+	}
+
+	<-g.ticker.C
+
+	locationData, err := fetchLocationData(city, country)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	coordinates = Coordinates{Lat: locationData.Lat, Lon: locationData.Lon}
+
+	g.mu.Lock()
+	g.data[key] = coordinates
+	g.mu.Unlock()
+
+	if err := g.save(); err != nil {
+		return coordinates, fmt.Errorf("Failed to save geocache: %s", err)
+	}
+
+	return coordinates, nil
+}
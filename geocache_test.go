@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const fakeGeoJSON = `[{"Name":"Helsinki","Lat":60.17,"Lon":24.94,"Country":"FI"}]`
+
+func TestGeoCacheDecodeCachesResult(t *testing.T) {
+	rt := withFakeTransport(t, fakeGeoJSON)
+	cache := newGeoCache(filepath.Join(t.TempDir(), "geocache.json"))
+
+	coords, err := cache.decode("helsinki", "fi")
+	if err != nil {
+		t.Fatalf("decode failed: %s", err)
+	}
+	if coords.Lat != 60.17 {
+		t.Fatalf("unexpected coordinates: %+v", coords)
+	}
+
+	if _, err := cache.decode("helsinki", "fi"); err != nil {
+		t.Fatalf("second decode failed: %s", err)
+	}
+
+	if rt.calls != 1 {
+		t.Fatalf("expected 1 HTTP call, got %d", rt.calls)
+	}
+}
+
+func TestGeoCacheThrottlesBursts(t *testing.T) {
+	withFakeTransport(t, fakeGeoJSON)
+	cache := newGeoCache(filepath.Join(t.TempDir(), "geocache.json"))
+	cache.ticker = time.NewTicker(50 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := cache.decode("helsinki", "fi"); err != nil {
+		t.Fatalf("decode failed: %s", err)
+	}
+	if _, err := cache.decode("tampere", "fi"); err != nil {
+		t.Fatalf("decode failed: %s", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected throttled calls to take at least 50ms, took %s", elapsed)
+	}
+}
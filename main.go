@@ -4,13 +4,17 @@ import (
 	"bufio"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -19,10 +23,18 @@ var API_KEY = os.Getenv("API_KEY")
 
 var config Config
 
+// configMu guards config, since it can be mutated concurrently by the REPL
+// and by the HTTP server started with -serve.
+var configMu sync.RWMutex
+
 type Config struct {
-	Language   string
-	Units      string
-	Favourites []Location
+	Language         string
+	Units            string
+	Favourites       []Location
+	CacheLocation    string
+	CacheTTL         string
+	PrefetchSchedule string
+	Format           string
 }
 
 type Location struct {
@@ -100,7 +112,40 @@ type WeatherResult struct {
 	City        string
 }
 
+func toWeatherResult(result WeatherResponse) WeatherResult {
+	return WeatherResult{
+		Temperature: result.Temperatures.Temp,
+		FeelsLike:   result.Temperatures.FeelsLike,
+		Description: result.WeatherDetails[0].Description,
+		Country:     result.LocationDetails.Country,
+		City:        result.City,
+	}
+}
+
+// fetchWeather serves coordinates from the in-memory prefetch cache when
+// available, falling back to fetchWeatherFresh otherwise.
 func fetchWeather(coordinates Coordinates, weatherChan chan WeatherResult, errorChan chan error) {
+	key := cacheKey(coordinates)
+	if cached, ok := peakRequests.Load(key); ok {
+		entry := cached.(peakEntry)
+		if time.Since(entry.storedAt) <= cacheTTL() {
+			weatherChan <- entry.result
+			return
+		}
+		peakRequests.Delete(key)
+	}
+
+	fetchWeatherFresh(coordinates, weatherChan, errorChan)
+}
+
+// fetchWeatherFresh bypasses peakRequests, so the prefetch scheduler can
+// always refresh it from the on-disk cache or the API.
+func fetchWeatherFresh(coordinates Coordinates, weatherChan chan WeatherResult, errorChan chan error) {
+	if cached, err := loadFromDisk(coordinates); err == nil {
+		weatherChan <- toWeatherResult(cached)
+		return
+	}
+
 	url := fmt.Sprintf("https://api.openweathermap.org/data/2.5/weather?lat=%f&lon=%f&units=metric&lang=%s&appid=%s", coordinates.Lat, coordinates.Lon, config.Language, API_KEY)
 	data, err := fetchData(url)
 	if err != nil {
@@ -116,14 +161,11 @@ func fetchWeather(coordinates Coordinates, weatherChan chan WeatherResult, error
 		return
 	}
 
-	res := WeatherResult{
-		Temperature: result.Temperatures.Temp,
-		FeelsLike:   result.Temperatures.FeelsLike,
-		Description: result.WeatherDetails[0].Description,
-		Country:     result.LocationDetails.Country,
-		City:        result.City,
+	if err := saveToDisk(coordinates, data); err != nil {
+		log.Printf("Failed to cache weather response: %s", err)
 	}
-	weatherChan <- res
+
+	weatherChan <- toWeatherResult(result)
 }
 
 func printResult(result WeatherResult) {
@@ -132,13 +174,54 @@ func printResult(result WeatherResult) {
 	fmt.Println("--------------------------------------------------------")
 }
 
+// snapshotFavourites returns a copy of config.Favourites taken under
+// configMu, safe to range over without holding the lock.
+func snapshotFavourites() []Location {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	favourites := make([]Location, len(config.Favourites))
+	copy(favourites, config.Favourites)
+	return favourites
+}
+
 func fetchFavourites(weatherChan chan WeatherResult, errorChan chan error) {
-	if len(config.Favourites) == 0 {
+	favourites := snapshotFavourites()
+
+	if len(favourites) == 0 {
 		fmt.Println("No favourites added")
 	}
-	for i := 0; i < len(config.Favourites); i++ {
-		go fetchWeather(config.Favourites[i].Coordinates, weatherChan, errorChan)
+	for i := 0; i < len(favourites); i++ {
+		go fetchWeather(favourites[i].Coordinates, weatherChan, errorChan)
+	}
+}
+
+// collectFavourites fetches the weather for every favourite and waits for
+// all of them to resolve, so callers can format them as a single block.
+func collectFavourites() []WeatherResult {
+	favourites := snapshotFavourites()
+	n := len(favourites)
+	if n == 0 {
+		fmt.Println("No favourites added")
+		return nil
+	}
+
+	weatherChan := make(chan WeatherResult, n)
+	errorChan := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go fetchWeather(favourites[i].Coordinates, weatherChan, errorChan)
+	}
+
+	results := make([]WeatherResult, 0, n)
+	for i := 0; i < n; i++ {
+		select {
+		case res := <-weatherChan:
+			results = append(results, res)
+		case err := <-errorChan:
+			fmt.Println(err)
+		}
 	}
+	return results
 }
 
 func readConfigFile() error {
@@ -158,7 +241,7 @@ func readConfigFile() error {
 }
 
 func createNewConfigFile() error {
-	file, err := json.Marshal(Config{Units: "metric", Language: "en", Favourites: []Location{}})
+	file, err := json.Marshal(Config{Units: "metric", Language: "en", Favourites: []Location{}, CacheLocation: defaultCacheLocation, CacheTTL: defaultCacheTTL.String(), Format: "pretty"})
 	if err != nil {
 		return err
 	}
@@ -179,7 +262,8 @@ func saveConfig(config []byte) error {
 }
 
 func listFavourites() {
-	favourites := config.Favourites
+	favourites := snapshotFavourites()
+
 	if len(favourites) == 0 {
 		fmt.Println("No favourites added")
 	}
@@ -191,6 +275,9 @@ func listFavourites() {
 }
 
 func removeFavourite(city string) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
 	favourites := config.Favourites
 	var index int = -1
 	for i := 0; i < len(favourites); i++ {
@@ -202,6 +289,7 @@ func removeFavourite(city string) error {
 	if index == -1 {
 		return fmt.Errorf("City %s does not exist in favourites", city)
 	}
+	peakRequests.Delete(cacheKey(favourites[index].Coordinates))
 	config.Favourites = append(config.Favourites[:index], config.Favourites[index+1:]...)
 	configBytes, err := json.Marshal(config)
 	if err != nil {
@@ -216,6 +304,9 @@ func removeFavourite(city string) error {
 }
 
 func addFavourite(city, country string) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
 	for i := 0; i < len(config.Favourites); i++ {
 		c := config.Favourites[i].City
 		if strings.ToLower(c) == strings.ToLower(city) {
@@ -223,12 +314,11 @@ func addFavourite(city, country string) error {
 		}
 	}
 
-	locationData, err := fetchLocationData(city, country)
+	coordinates, err := geoCache.decode(city, country)
 	if err != nil {
 		return err
 	}
-	coordinates := Coordinates{Lat: locationData.Lat, Lon: locationData.Lon}
-	newFavourites := append(config.Favourites, Location{City: locationData.Name, Country: locationData.Country, Coordinates: coordinates})
+	newFavourites := append(config.Favourites, Location{City: strings.Title(city), Country: strings.ToUpper(country), Coordinates: coordinates})
 	config.Favourites = newFavourites
 	configBytes, err := json.Marshal(config)
 	if err != nil {
@@ -238,17 +328,16 @@ func addFavourite(city, country string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to save config file: %s", err)
 	}
-	fmt.Printf("New location %s, %s added to favourites\n", locationData.Name, locationData.Country)
+	fmt.Printf("New location %s, %s added to favourites\n", strings.Title(city), strings.ToUpper(country))
 	return nil
 }
 
 func getWeatherByCity(city, country string, weatherChan chan WeatherResult, errorChan chan error) {
-	data, err := fetchLocationData(city, country)
+	coordinates, err := geoCache.decode(city, country)
 	if err != nil {
 		errorChan <- err
 		return
 	}
-	coordinates := Coordinates{Lat: data.Lat, Lon: data.Lon}
 	go fetchWeather(coordinates, weatherChan, errorChan)
 }
 
@@ -260,6 +349,10 @@ func printCommands() {
 	fmt.Fprintln(w, "list\t\t\t|\tList favourites")
 	fmt.Fprintln(w, "fav\t<City>\t[<Country>]\t|\tAdd city to favourites")
 	fmt.Fprintln(w, "remove\t<City>\t\t|\tRemove city from favourites")
+	fmt.Fprintln(w, "prefetch\t\t\t|\tPre-fetch weather for all favourites now")
+	fmt.Fprintln(w, "forecast\t<City>\t[<Country>] [<Days>]\t|\tGet a 3-7 day forecast for a city")
+	fmt.Fprintln(w, "moon\t[<Date>]\t\t|\tGet the moon phase for today or a given date")
+	fmt.Fprintln(w, "alerts\t<City>\t[<Country>]\t|\tGet active weather alerts for a city")
 	fmt.Fprintln(w, "help\t\t\t|\tList available commands")
 	fmt.Fprintln(w, "\n---------------------------------------------")
 }
@@ -279,7 +372,17 @@ func handleInput(cmdChan chan []string) {
 	}
 }
 
-func handleCommand(input []string, weatherChan chan WeatherResult, errorChan chan error) {
+// popFormatOverride strips a trailing format name (e.g. "json", "table") from
+// input, if present, and returns the remaining arguments plus the format to
+// use (falling back to format).
+func popFormatOverride(input []string, format string) ([]string, string) {
+	if len(input) > 1 && isFormatName(input[len(input)-1]) {
+		return input[:len(input)-1], input[len(input)-1]
+	}
+	return input, format
+}
+
+func handleCommand(input []string, errorChan chan error) {
 	if len(input) == 0 {
 		return
 	}
@@ -297,15 +400,39 @@ func handleCommand(input []string, weatherChan chan WeatherResult, errorChan cha
 
 	switch command {
 	case "w":
-		if len(input) < 2 {
+		wInput, formatName := popFormatOverride(input, config.Format)
+		formatter, err := getFormatter(formatName)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		if len(wInput) < 2 {
 			errorChan <- errors.New("Missing city parameter")
 			return
 		}
-		getWeatherByCity(city, country, weatherChan, errorChan)
-		printResult(<-weatherChan)
+		city := strings.ToLower(wInput[1])
+		country := ""
+		if len(wInput) > 2 {
+			country = strings.ToLower(wInput[2])
+		}
+		localWeatherChan := make(chan WeatherResult, 1)
+		localErrorChan := make(chan error, 1)
+		getWeatherByCity(city, country, localWeatherChan, localErrorChan)
+		select {
+		case res := <-localWeatherChan:
+			formatter.Format([]WeatherResult{res})
+		case err := <-localErrorChan:
+			errorChan <- err
+		}
 
 	case "f":
-		fetchFavourites(weatherChan, errorChan)
+		_, formatName := popFormatOverride(input, config.Format)
+		formatter, err := getFormatter(formatName)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		formatter.Format(collectFavourites())
 
 	case "list":
 		listFavourites()
@@ -326,6 +453,75 @@ func handleCommand(input []string, weatherChan chan WeatherResult, errorChan cha
 			errorChan <- err
 		}
 
+	case "prefetch":
+		runPrefetch()
+
+	case "forecast":
+		if len(input) < 2 {
+			errorChan <- errors.New("Missing city parameter")
+			return
+		}
+		args := input[1:]
+		days := 5
+		if d, parseErr := strconv.Atoi(args[len(args)-1]); parseErr == nil {
+			days = d
+			args = args[:len(args)-1]
+		}
+		forecastCountry := ""
+		if len(args) > 1 {
+			forecastCountry = args[1]
+		}
+		coordinates, err := geoCache.decode(args[0], forecastCountry)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		forecast, err := fetchForecast(coordinates)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		printForecast(forecast, days)
+
+	case "moon":
+		favourites := snapshotFavourites()
+		if len(favourites) == 0 {
+			errorChan <- errors.New("Add a favourite first to look up the moon phase")
+			return
+		}
+		var date string
+		if len(input) > 1 {
+			date = input[1]
+		}
+		forecast, err := fetchForecast(favourites[0].Coordinates)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		phase, err := moonPhaseForDate(forecast, date)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		printMoonPhase(phase)
+
+	case "alerts":
+		if len(input) < 2 {
+			errorChan <- errors.New("Missing city parameter")
+			return
+		}
+		coordinates, err := geoCache.decode(city, country)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		forecast, err := fetchForecast(coordinates)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		printAlerts(forecast.Alerts)
+
 	case "help":
 		printCommands()
 
@@ -336,27 +532,40 @@ func handleCommand(input []string, weatherChan chan WeatherResult, errorChan cha
 }
 
 func main() {
+	format := flag.String("format", "", "Output format: pretty, json, oneline or table")
+	serve := flag.String("serve", "", "Address to serve the read-only HTTP API on, e.g. :8080")
+	flag.Parse()
+
 	err := readConfigFile()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	weatherChan := make(chan WeatherResult)
+	if err := geoCache.load(); err != nil {
+		log.Printf("Failed to load geocache: %s", err)
+	}
+
+	if *format != "" {
+		config.Format = *format
+	}
+
 	errorChan := make(chan error)
 	cmdChan := make(chan []string)
 
 	printCommands()
 
 	go handleInput(cmdChan)
+	go startPrefetchScheduler()
+	if *serve != "" {
+		go startServer(*serve)
+	}
 
 	for {
 		fmt.Print("\nCommand: ")
 
 		select {
 		case cmd := <-cmdChan:
-			go handleCommand(cmd, weatherChan, errorChan, &wg)
-		case res := <-weatherChan:
-			printResult(res)
+			go handleCommand(cmd, errorChan)
 		case err := <-errorChan:
 			fmt.Println(err)
 		}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// peakRequests holds the most recently prefetched result for each favourite,
+// keyed by cacheKey(location.Coordinates). Entries are only served while
+// younger than cacheTTL(), same as the on-disk cache.
+var peakRequests sync.Map
+
+type peakEntry struct {
+	result   WeatherResult
+	storedAt time.Time
+}
+
+func prefetchRequest(location Location) {
+	weatherChan := make(chan WeatherResult, 1)
+	errorChan := make(chan error, 1)
+
+	fetchWeatherFresh(location.Coordinates, weatherChan, errorChan)
+
+	select {
+	case res := <-weatherChan:
+		peakRequests.Store(cacheKey(location.Coordinates), peakEntry{result: res, storedAt: time.Now()})
+		slog.Info("prefetch succeeded", "city", location.City, "country", location.Country)
+	case err := <-errorChan:
+		slog.Error("prefetch failed", "city", location.City, "country", location.Country, "error", err)
+	}
+}
+
+func runPrefetch() {
+	favourites := snapshotFavourites()
+	for i := 0; i < len(favourites); i++ {
+		go prefetchRequest(favourites[i])
+	}
+}
+
+// startPrefetchScheduler periodically calls runPrefetch according to
+// Config.PrefetchSchedule, which accepts either a duration (e.g. "15m") or a
+// cron expression (e.g. "*/15 * * * *"). It blocks, so callers should run it
+// in its own goroutine.
+func startPrefetchScheduler() {
+	schedule := config.PrefetchSchedule
+	if schedule == "" {
+		return
+	}
+
+	if interval, err := time.ParseDuration(schedule); err == nil {
+		ticker := time.NewTicker(interval)
+		for range ticker.C {
+			runPrefetch()
+		}
+		return
+	}
+
+	c := cron.New()
+	if _, err := c.AddFunc(schedule, runPrefetch); err != nil {
+		slog.Error("invalid prefetch schedule", "schedule", schedule, "error", err)
+		return
+	}
+	c.Run()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// startServer runs a read-only HTTP server alongside the REPL, exposing
+// weather and favourites over localhost. It reuses the same cache/prefetch
+// layer and config as the REPL, guarded by configMu.
+func startServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/weather", handleWeather)
+	mux.HandleFunc("/favourites", handleFavourites)
+	mux.HandleFunc("/favourites/", handleFavourite)
+
+	log.Printf("Serving weather API on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func wantsPlainText(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") && !strings.Contains(accept, "application/json")
+}
+
+func writeWeatherResult(w http.ResponseWriter, r *http.Request, result WeatherResult) {
+	if wantsPlainText(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, oneLineString(result))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func handleWeather(w http.ResponseWriter, r *http.Request) {
+	city := strings.ToLower(r.URL.Query().Get("city"))
+	country := strings.ToLower(r.URL.Query().Get("country"))
+	if city == "" {
+		http.Error(w, "Missing city parameter", http.StatusBadRequest)
+		return
+	}
+
+	weatherChan := make(chan WeatherResult, 1)
+	errorChan := make(chan error, 1)
+	getWeatherByCity(city, country, weatherChan, errorChan)
+
+	select {
+	case res := <-weatherChan:
+		writeWeatherResult(w, r, res)
+	case err := <-errorChan:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
+
+func handleFavourites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	favourites := snapshotFavourites()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(favourites)
+}
+
+func handleFavourite(w http.ResponseWriter, r *http.Request) {
+	city := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/favourites/"))
+	if city == "" {
+		http.Error(w, "Missing city", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		country := strings.ToLower(r.URL.Query().Get("country"))
+		if err := addFavourite(city, country); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodDelete:
+		if err := removeFavourite(city); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
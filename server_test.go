@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleWeatherJSONAndPlainText(t *testing.T) {
+	config.CacheLocation = t.TempDir()
+	config.CacheTTL = defaultCacheTTL.String()
+	geoCache = newGeoCache(t.TempDir() + "/geocache.json")
+	geoCache.data[geoCacheKey("london", "")] = Coordinates{Lat: 51.5, Lon: -0.1}
+	withFakeTransport(t, fakeWeatherJSON)
+
+	server := httptest.NewServer(http.HandlerFunc(handleWeather))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "?city=london")
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json, got %s", ct)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?city=london", nil)
+	req.Header.Set("Accept", "text/plain")
+	res2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	defer res2.Body.Close()
+	if ct := res2.Header.Get("Content-Type"); ct[:10] != "text/plain" {
+		t.Fatalf("expected text/plain, got %s", ct)
+	}
+}
+
+func TestHandleFavouritesAddAndRemove(t *testing.T) {
+	config.Favourites = []Location{}
+	geoCache = newGeoCache(t.TempDir() + "/geocache.json")
+	withFakeTransport(t, `[{"Name":"Helsinki","Lat":60.17,"Lon":24.94,"Country":"FI"}]`)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/favourites", handleFavourites)
+	mux.HandleFunc("/favourites/", handleFavourite)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/favourites/helsinki?country=fi", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.StatusCode)
+	}
+
+	listRes, err := http.Get(server.URL + "/favourites")
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	defer listRes.Body.Close()
+	if listRes.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", listRes.StatusCode)
+	}
+
+	delReq, _ := http.NewRequest(http.MethodDelete, server.URL+"/favourites/helsinki", nil)
+	delRes, err := http.DefaultClient.Do(delReq)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	delRes.Body.Close()
+	if delRes.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", delRes.StatusCode)
+	}
+}